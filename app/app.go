@@ -0,0 +1,132 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thiagobgarc/orders-api/pubsub"
+	"github.com/thiagobgarc/orders-api/repository/order"
+)
+
+type App struct {
+	router http.Handler
+
+	// rdb is only non-nil when config.Backend is "redis"; it backs both
+	// RedisRepo and the WebSocket stream endpoint's pub/sub subscription.
+	rdb *redis.Client
+
+	// pgPool is only non-nil when config.Backend is "postgres".
+	pgPool *pgxpool.Pool
+
+	repo   order.Repo
+	config Config
+
+	// ready backs /readyz: true while serving normally, flipped to false
+	// the moment shutdown begins so load balancers stop routing here.
+	ready atomic.Bool
+
+	// inFlight is held by every request for its duration, so Start can wait
+	// for handlers - including long-lived ones like the WebSocket stream,
+	// which http.Server.Shutdown doesn't track once hijacked - to finish.
+	inFlight sync.WaitGroup
+}
+
+func New(config Config) *App {
+	app := &App{config: config}
+	app.ready.Store(true)
+
+	switch config.Backend {
+	case "memory":
+		app.repo = order.NewInMemoryRepo()
+	case "postgres":
+		pool, err := pgxpool.New(context.Background(), config.PostgresURL)
+		if err != nil {
+			fmt.Println("failed to create postgres pool:", err)
+		}
+		app.pgPool = pool
+		app.repo = order.NewPostgresRepo(pool)
+	default:
+		app.rdb = redis.NewClient(&redis.Options{
+			Addr: config.RedisAddress,
+		})
+		app.repo = &order.RedisRepo{
+			Client:    app.rdb,
+			Publisher: &pubsub.Publisher{Client: app.rdb},
+		}
+	}
+
+	app.loadRoutes()
+
+	return app
+}
+
+func (a *App) Start(ctx context.Context) error {
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", a.config.ServerPort),
+		Handler: a.router,
+	}
+
+	if a.rdb != nil {
+		if err := a.rdb.Ping(ctx).Err(); err != nil {
+			return fmt.Errorf("failed to connect to redis: %w", err)
+		}
+		defer func() {
+			if err := a.rdb.Close(); err != nil {
+				fmt.Println("failed to close redis", err)
+			}
+		}()
+	}
+
+	if a.pgPool != nil {
+		if err := a.pgPool.Ping(ctx); err != nil {
+			return fmt.Errorf("failed to connect to postgres: %w", err)
+		}
+		defer a.pgPool.Close()
+	}
+
+	fmt.Println("Starting server")
+
+	ch := make(chan error, 1)
+
+	go func() {
+		err := server.ListenAndServe()
+		if err != nil {
+			ch <- fmt.Errorf("failed to start server: %w", err)
+		}
+		close(ch)
+	}()
+
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		a.ready.Store(false)
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.config.ShutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("failed to shut down gracefully: %w", err)
+		}
+
+		drained := make(chan struct{})
+		go func() {
+			a.inFlight.Wait()
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+		case <-shutdownCtx.Done():
+			fmt.Println("shutdown timeout exceeded with requests still in flight")
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,68 @@
+package application
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const defaultShutdownTimeout = 30 * time.Second
+
+type Config struct {
+	RedisAddress string
+	ServerPort   uint64
+
+	// Backend selects the order.Repo implementation: "redis" (default),
+	// "memory", or "postgres".
+	Backend     string
+	PostgresURL string
+
+	// ShutdownTimeout bounds how long Start waits for in-flight requests to
+	// drain once the context passed to it is canceled.
+	ShutdownTimeout time.Duration
+
+	// StreamAuthSecret signs the per-customer tokens the WebSocket stream
+	// endpoint requires, so knowing a customer_id isn't enough to read that
+	// customer's order events. Empty disables streaming entirely rather
+	// than serving it unauthenticated.
+	StreamAuthSecret string
+}
+
+// LoadConfig builds a Config from the process environment, falling back to
+// sane local-development defaults for anything that isn't set.
+func LoadConfig() Config {
+	cfg := Config{
+		RedisAddress:    "localhost:6379",
+		ServerPort:      3000,
+		Backend:         "redis",
+		PostgresURL:     "postgres://localhost:5432/orders",
+		ShutdownTimeout: defaultShutdownTimeout,
+	}
+
+	if redisAddress, exists := os.LookupEnv("REDIS_ADDR"); exists {
+		cfg.RedisAddress = redisAddress
+	}
+
+	if backend, exists := os.LookupEnv("ORDERS_BACKEND"); exists {
+		cfg.Backend = backend
+	}
+
+	if postgresURL, exists := os.LookupEnv("POSTGRES_URL"); exists {
+		cfg.PostgresURL = postgresURL
+	}
+
+	if shutdownTimeout, exists := os.LookupEnv("SHUTDOWN_TIMEOUT"); exists {
+		timeout, err := time.ParseDuration(shutdownTimeout)
+		if err != nil {
+			fmt.Println("invalid SHUTDOWN_TIMEOUT, using default:", err)
+		} else {
+			cfg.ShutdownTimeout = timeout
+		}
+	}
+
+	if streamAuthSecret, exists := os.LookupEnv("STREAM_AUTH_SECRET"); exists {
+		cfg.StreamAuthSecret = streamAuthSecret
+	}
+
+	return cfg
+}
@@ -9,26 +9,57 @@ import (
 	"github.com/thiagobgarc/orders-api/handler"
 )
 
-func loadRoutes() *chi.Mux {
+func (a *App) loadRoutes() {
 	router := chi.NewRouter()
 
 	router.Use(middleware.Logger)
+	router.Use(a.trackInFlight)
 
 	router.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	})
 
-	router.Route("/orders", loadOrderRoutes)
+	router.Get("/readyz", a.readyz)
 
-	return router
+	router.Route("/orders", a.loadOrderRoutes)
+
+	a.router = router
+}
+
+// trackInFlight holds a.inFlight for the duration of every request, so
+// Start can wait for requests to finish draining during shutdown.
+func (a *App) trackInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.inFlight.Add(1)
+		defer a.inFlight.Done()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// readyz reports 503 once shutdown has begun, so load balancers stop
+// routing new traffic here while in-flight requests drain.
+func (a *App) readyz(w http.ResponseWriter, r *http.Request) {
+	if !a.ready.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
 }
 
-func loadOrderRoutes(router chi.Router) {
-	orderhandler := &handler.Order{}
+func (a *App) loadOrderRoutes(router chi.Router) {
+	orderhandler := &handler.Order{
+		Repo:             a.repo,
+		Redis:            a.rdb,
+		StreamAuthSecret: a.config.StreamAuthSecret,
+	}
 
 	router.Post("/", orderhandler.Create)
 	router.Get("/", orderhandler.List)
+	router.Get("/by-client-oid/{uuid}", orderhandler.GetByClientOID)
 	router.Get("/{id}", orderhandler.GetByID)
 	router.Put("/{id}", orderhandler.UpdateByID)
 	router.Delete("/{id}", orderhandler.DeleteByID)
+	router.Get("/stream", orderhandler.Stream)
 }
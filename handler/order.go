@@ -1,28 +1,321 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thiagobgarc/orders-api/model"
+	"github.com/thiagobgarc/orders-api/repository/order"
 )
 
-type Order struct{}
+type Order struct {
+	Repo order.Repo
+
+	// Redis is only set when the selected backend is Redis; it backs the
+	// WebSocket stream endpoint, which isn't part of the pluggable Repo
+	// contract. The client-OID lookup goes through Repo (clientOIDFinder).
+	Redis *redis.Client
 
-func (o *Order) Create(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "Create Order")
+	// StreamAuthSecret signs the per-customer tokens Stream requires; see
+	// stream.go. Empty disables Stream entirely.
+	StreamAuthSecret string
 }
 
-func (o *Order) List(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "List Order")
+// clientOIDFinder is an optional capability some Repo implementations
+// support. It's a separate interface rather than part of Repo because
+// idempotent-create lookups aren't meaningful for every backend.
+type clientOIDFinder interface {
+	FindByClientOID(ctx context.Context, clientOID uuid.UUID) (model.Order, error)
 }
 
-func (o *Order) GetByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "Get Order by ID")
+func (e *Order) Create(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		CustomerID uuid.UUID        `json:"customer_id"`
+		ClientOID  uuid.UUID        `json:"client_oid"`
+		LineItems  []model.LineItem `json:"line_items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	newOrder := model.Order{
+		OrderID:    newOrderID(),
+		CustomerID: body.CustomerID,
+		ClientOID:  body.ClientOID,
+		LineItems:  body.LineItems,
+		CreatedAt:  &now,
+	}
+
+	newOrder, err := e.Repo.Insert(r.Context(), newOrder)
+	if err != nil {
+		fmt.Println("failed to insert order:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	res, err := json.Marshal(newOrder)
+	if err != nil {
+		fmt.Println("failed to marshal order:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	w.Write(res)
 }
 
-func (o *Order) UpdateByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "Update Order By ID")
+func (e *Order) List(w http.ResponseWriter, r *http.Request) {
+	query, err := parseOrderQuery(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	res, err := e.Repo.FindOrders(r.Context(), query)
+	if err != nil {
+		fmt.Println("failed to find orders:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Items []model.Order `json:"items"`
+		Next  uint64        `json:"next,omitempty"`
+	}{
+		Items: res.Orders,
+		Next:  res.Cursor,
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		fmt.Println("failed to marshal orders:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(data)
 }
 
-func (o *Order) DeleteByID(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprintf(w, "Delete Order By ID")
+func (e *Order) GetByID(w http.ResponseWriter, r *http.Request) {
+	orderID, err := parseOrderID(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	foundOrder, err := e.Repo.FindByID(r.Context(), orderID)
+	if errors.Is(err, order.ErrNotExist) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Println("failed to find order by id:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(foundOrder); err != nil {
+		fmt.Println("failed to marshal order:", err)
+		return
+	}
+}
+
+func (e *Order) GetByClientOID(w http.ResponseWriter, r *http.Request) {
+	clientOID, err := uuid.Parse(chi.URLParam(r, "uuid"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	finder, ok := e.Repo.(clientOIDFinder)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	foundOrder, err := finder.FindByClientOID(r.Context(), clientOID)
+	if errors.Is(err, order.ErrNotExist) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Println("failed to find order by client oid:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(foundOrder); err != nil {
+		fmt.Println("failed to marshal order:", err)
+		return
+	}
+}
+
+func (e *Order) UpdateByID(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Status  string `json:"status"`
+		Version uint64 `json:"version"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	orderID, err := parseOrderID(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	theOrder, err := e.Repo.FindByID(r.Context(), orderID)
+	if errors.Is(err, order.ErrNotExist) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Println("failed to find order by id:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC()
+	theOrder.Version = body.Version
+
+	switch body.Status {
+	case "shipped":
+		theOrder.ShippedAt = &now
+	case "completed":
+		theOrder.CompletedAt = &now
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	updated, err := e.Repo.Update(r.Context(), theOrder)
+	if errors.Is(err, order.ErrVersionConflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(struct {
+			Error          string `json:"error"`
+			CurrentVersion uint64 `json:"current_version"`
+		}{
+			Error:          "version conflict",
+			CurrentVersion: updated.Version,
+		})
+		return
+	} else if errors.Is(err, order.ErrNotExist) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Println("failed to update order:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if err := json.NewEncoder(w).Encode(updated); err != nil {
+		fmt.Println("failed to marshal order:", err)
+		return
+	}
+}
+
+func (e *Order) DeleteByID(w http.ResponseWriter, r *http.Request) {
+	orderID, err := parseOrderID(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := e.Repo.DeletedByID(r.Context(), orderID); errors.Is(err, order.ErrNotExist) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		fmt.Println("failed to delete order:", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+}
+
+// newOrderID generates an OrderID clamped to the signed 64-bit range. Every
+// backend stores OrderID as uint64, but PostgresRepo encodes it into a
+// BIGINT column, and pgx refuses to write a uint64 value above
+// math.MaxInt64 - so the full unsigned range isn't actually usable.
+func newOrderID() uint64 {
+	return rand.Uint64() & math.MaxInt64
+}
+
+func parseOrderID(r *http.Request) (uint64, error) {
+	idParam := chi.URLParam(r, "id")
+
+	const base = 10
+	const bitSize = 64
+	return strconv.ParseUint(idParam, base, bitSize)
+}
+
+// defaultListSize caps how many orders List returns per page when the
+// caller doesn't specify a size.
+const defaultListSize = 50
+
+// parseOrderQuery builds an order.OrderQuery from
+// ?customer_id=&status=&since=&until=&size=&cursor=, all of which are
+// optional.
+func parseOrderQuery(r *http.Request) (order.OrderQuery, error) {
+	q := r.URL.Query()
+
+	query := order.OrderQuery{
+		Status: q.Get("status"),
+		Size:   defaultListSize,
+	}
+
+	if customerIDParam := q.Get("customer_id"); customerIDParam != "" {
+		customerID, err := uuid.Parse(customerIDParam)
+		if err != nil {
+			return order.OrderQuery{}, err
+		}
+		query.CustomerID = customerID
+	}
+
+	if sinceParam := q.Get("since"); sinceParam != "" {
+		since, err := time.Parse(time.RFC3339, sinceParam)
+		if err != nil {
+			return order.OrderQuery{}, err
+		}
+		query.CreatedAfter = &since
+	}
+
+	if untilParam := q.Get("until"); untilParam != "" {
+		until, err := time.Parse(time.RFC3339, untilParam)
+		if err != nil {
+			return order.OrderQuery{}, err
+		}
+		query.CreatedBefore = &until
+	}
+
+	const base = 10
+	const bitSize = 64
+
+	if sizeParam := q.Get("size"); sizeParam != "" {
+		size, err := strconv.ParseUint(sizeParam, base, bitSize)
+		if err != nil {
+			return order.OrderQuery{}, err
+		}
+		query.Size = size
+	}
+
+	if cursorParam := q.Get("cursor"); cursorParam != "" {
+		cursor, err := strconv.ParseUint(cursorParam, base, bitSize)
+		if err != nil {
+			return order.OrderQuery{}, err
+		}
+		query.Offset = cursor
+	}
+
+	return query, nil
 }
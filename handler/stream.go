@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+
+	"github.com/thiagobgarc/orders-api/pubsub"
+)
+
+var streamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// customerStreamToken derives the token a caller must present to stream a
+// given customer's events, so knowing or guessing a customer_id alone isn't
+// enough to read someone else's order activity.
+func customerStreamToken(secret, customerID string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(customerID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Stream upgrades the connection and streams order lifecycle events
+// (created, updated, shipped, completed, deleted) for a single customer
+// until the client disconnects. Callers must pass a `token` query parameter
+// equal to customerStreamToken(StreamAuthSecret, customer_id) - there's no
+// broader session/identity system in this service yet to authenticate
+// against, so this is deliberately the narrowest thing that stops a caller
+// from streaming a customer_id they weren't given a token for.
+func (e *Order) Stream(w http.ResponseWriter, r *http.Request) {
+	if e.Redis == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+
+	if e.StreamAuthSecret == "" {
+		fmt.Println("stream auth secret not configured; refusing to stream")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	customerID, err := uuid.Parse(r.URL.Query().Get("customer_id"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	token := r.URL.Query().Get("token")
+	want := customerStreamToken(e.StreamAuthSecret, customerID.String())
+	if subtle.ConstantTimeCompare([]byte(token), []byte(want)) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	conn, err := streamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fmt.Println("failed to upgrade connection:", err)
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// The client doesn't send anything over this connection, but we still
+	// need to read in order to notice a close frame or a dropped socket.
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	sub := pubsub.Subscribe(ctx, e.Redis, pubsub.CustomerChannel(customerID))
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-sub.Events:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -5,14 +5,15 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
 
 	application "github.com/thiagobgarc/orders-api/app"
 )
 
 func main() {
-	app := application.New()
+	app := application.New(application.LoadConfig())
 
-	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	err := app.Start(ctx)
@@ -9,10 +9,17 @@ import (
 type Order struct {
 	OrderID     uint64
 	CustomerID  uuid.UUID
+	ClientOID   uuid.UUID
 	LineItems   []LineItem
 	CreatedAt   *time.Time
 	ShippedAt   *time.Time
 	CompletedAt *time.Time
+
+	// Version is incremented on every successful update. Callers must send
+	// back the version they last read; a stale version is rejected with
+	// ErrVersionConflict so concurrent editors can't silently clobber each
+	// other's changes.
+	Version uint64
 }
 
 type LineItem struct {
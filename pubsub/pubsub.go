@@ -0,0 +1,150 @@
+// Package pubsub decouples order state changes from the consumers that
+// care about them. Every order mutation is published as an event to Redis;
+// anything downstream (the WebSocket stream handler today, maybe a worker
+// or an audit log tomorrow) subscribes to the channels it needs instead of
+// being wired directly into the repository.
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+type EventKind string
+
+const (
+	EventOrderCreated   EventKind = "created"
+	EventOrderUpdated   EventKind = "updated"
+	EventOrderShipped   EventKind = "shipped"
+	EventOrderCompleted EventKind = "completed"
+	EventOrderDeleted   EventKind = "deleted"
+)
+
+// GlobalChannel carries every order event regardless of customer.
+const GlobalChannel = "orders:events"
+
+// CustomerChannel returns the channel carrying only the events belonging
+// to the given customer.
+func CustomerChannel(customerID uuid.UUID) string {
+	return fmt.Sprintf("orders:events:%s", customerID)
+}
+
+// OrderEvent is the payload published on both the global and per-customer
+// channels whenever an order is created, updated or deleted.
+type OrderEvent struct {
+	Kind       EventKind   `json:"kind"`
+	OrderID    uint64      `json:"order_id"`
+	CustomerID uuid.UUID   `json:"customer_id"`
+	Timestamp  time.Time   `json:"timestamp"`
+	Order      model.Order `json:"order"`
+}
+
+// Publisher publishes order events to Redis.
+type Publisher struct {
+	Client *redis.Client
+}
+
+// Publish fans an event out to the global channel and the channel scoped
+// to the event's customer, inside a single pipeline.
+func (p *Publisher) Publish(ctx context.Context, event OrderEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode order event: %w", err)
+	}
+
+	pipe := p.Client.Pipeline()
+	pipe.Publish(ctx, GlobalChannel, data)
+	pipe.Publish(ctx, CustomerChannel(event.CustomerID), data)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to publish order event: %w", err)
+	}
+
+	return nil
+}
+
+// reconnectDelay is how long Subscribe waits before retrying after the
+// underlying Redis subscription drops.
+const reconnectDelay = 2 * time.Second
+
+// Subscription is a single consumer's view of a channel: decoded events on
+// Events, and any transient subscribe errors (already retried in the
+// background) on Errors.
+type Subscription struct {
+	Events <-chan OrderEvent
+	Errors <-chan error
+}
+
+// Subscribe subscribes to channel and fans out decoded events to the
+// returned Subscription until ctx is canceled. Transient Redis errors
+// (dropped connections, etc.) are retried with a fixed backoff rather than
+// ending the subscription.
+func Subscribe(ctx context.Context, client *redis.Client, channel string) *Subscription {
+	events := make(chan OrderEvent)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(events)
+
+		for ctx.Err() == nil {
+			sub := client.Subscribe(ctx, channel)
+
+			err := relay(ctx, sub, events)
+			sub.Close()
+
+			if ctx.Err() != nil {
+				return
+			}
+
+			if err != nil {
+				select {
+				case errs <- err:
+				default:
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(reconnectDelay):
+			}
+		}
+	}()
+
+	return &Subscription{Events: events, Errors: errs}
+}
+
+// relay copies messages from sub onto events until ctx is canceled or the
+// subscription's channel closes.
+func relay(ctx context.Context, sub *redis.PubSub, events chan<- OrderEvent) error {
+	ch := sub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return fmt.Errorf("pubsub: subscription to %q closed", sub.String())
+			}
+
+			var event OrderEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				continue
+			}
+
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package order
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+// InMemoryRepo is a Repo backed by a map guarded by a RWMutex. It's handy
+// for handler tests and for local development without a Redis instance;
+// it does not publish order events.
+type InMemoryRepo struct {
+	mu     sync.RWMutex
+	orders map[uint64]model.Order
+}
+
+func NewInMemoryRepo() *InMemoryRepo {
+	return &InMemoryRepo{
+		orders: make(map[uint64]model.Order),
+	}
+}
+
+func (r *InMemoryRepo) Insert(_ context.Context, order model.Order) (model.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if order.ClientOID != zeroUUID {
+		for _, existing := range r.orders {
+			if existing.ClientOID == order.ClientOID {
+				return existing, nil
+			}
+		}
+	}
+
+	r.orders[order.OrderID] = order
+
+	return order, nil
+}
+
+func (r *InMemoryRepo) FindByID(_ context.Context, id uint64) (model.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	order, exists := r.orders[id]
+	if !exists {
+		return model.Order{}, ErrNotExist
+	}
+
+	return order, nil
+}
+
+func (r *InMemoryRepo) FindByClientOID(_ context.Context, clientOID uuid.UUID) (model.Order, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, order := range r.orders {
+		if order.ClientOID == clientOID {
+			return order, nil
+		}
+	}
+
+	return model.Order{}, ErrNotExist
+}
+
+func (r *InMemoryRepo) Update(_ context.Context, order model.Order) (model.Order, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, exists := r.orders[order.OrderID]
+	if !exists {
+		return model.Order{}, ErrNotExist
+	}
+
+	if order.Version != current.Version {
+		return current, ErrVersionConflict
+	}
+
+	order.Version = current.Version + 1
+	r.orders[order.OrderID] = order
+
+	return order, nil
+}
+
+func (r *InMemoryRepo) DeletedByID(_ context.Context, id uint64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.orders[id]; !exists {
+		return ErrNotExist
+	}
+
+	delete(r.orders, id)
+
+	return nil
+}
+
+func (r *InMemoryRepo) FindOrders(_ context.Context, query OrderQuery) (FindResult, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matched := make([]model.Order, 0, len(r.orders))
+	for _, order := range r.orders {
+		if !matchesQuery(order, query) {
+			continue
+		}
+		matched = append(matched, order)
+	}
+	// Sort by creation time, not OrderID - OrderID is a random uint64
+	// (see newOrderID), so ordering by it would return history in an
+	// effectively random order and RedisRepo/PostgresRepo both order by
+	// created_at.
+	sort.Slice(matched, func(i, j int) bool { return createdAtScore(matched[i]) < createdAtScore(matched[j]) })
+
+	start := query.Offset
+	if start > uint64(len(matched)) {
+		start = uint64(len(matched))
+	}
+
+	end := uint64(len(matched))
+	if query.Size > 0 && start+query.Size < end {
+		end = start + query.Size
+	}
+
+	orders := make([]model.Order, 0, end-start)
+	orders = append(orders, matched[start:end]...)
+
+	var cursor uint64
+	if end < uint64(len(matched)) {
+		cursor = end
+	}
+
+	return FindResult{Orders: orders, Cursor: cursor}, nil
+}
+
+// matchesQuery applies an OrderQuery's optional filters in memory - there
+// are no indexes to lean on here, just a full scan.
+func matchesQuery(order model.Order, query OrderQuery) bool {
+	if query.CustomerID != zeroUUID && order.CustomerID != query.CustomerID {
+		return false
+	}
+
+	if query.Status != "" && statusOf(order) != query.Status {
+		return false
+	}
+
+	if query.CreatedAfter != nil && (order.CreatedAt == nil || order.CreatedAt.Before(*query.CreatedAfter)) {
+		return false
+	}
+
+	if query.CreatedBefore != nil && (order.CreatedAt == nil || order.CreatedAt.After(*query.CreatedBefore)) {
+		return false
+	}
+
+	return true
+}
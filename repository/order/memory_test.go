@@ -0,0 +1,111 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+// TestInMemoryRepo_Insert_ConcurrentClientOID guards the idempotent-create
+// contract: firing the same ClientOID at Insert from multiple goroutines at
+// once must still produce exactly one order, with every caller converging
+// on it rather than racing to create their own.
+func TestInMemoryRepo_Insert_ConcurrentClientOID(t *testing.T) {
+	repo := NewInMemoryRepo()
+	clientOID := uuid.New()
+
+	const callers = 20
+
+	var wg sync.WaitGroup
+	results := make([]model.Order, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			order, err := repo.Insert(context.Background(), model.Order{
+				OrderID:   uint64(i) + 1,
+				ClientOID: clientOID,
+			})
+			if err != nil {
+				t.Errorf("Insert: %v", err)
+				return
+			}
+			results[i] = order
+		}(i)
+	}
+	wg.Wait()
+
+	first := results[0].OrderID
+	for i, order := range results {
+		if order.OrderID != first {
+			t.Fatalf("caller %d got order %d, want %d (same ClientOID must resolve to one order)", i, order.OrderID, first)
+		}
+	}
+
+	repo.mu.RLock()
+	count := len(repo.orders)
+	repo.mu.RUnlock()
+
+	if count != 1 {
+		t.Fatalf("got %d stored orders for one ClientOID, want 1", count)
+	}
+}
+
+// TestInMemoryRepo_Update_ConcurrentVersion guards the optimistic-locking
+// contract: when multiple callers read the same version and race to update
+// it, exactly one may win; everyone else must get ErrVersionConflict rather
+// than silently clobbering the winner's write.
+func TestInMemoryRepo_Update_ConcurrentVersion(t *testing.T) {
+	repo := NewInMemoryRepo()
+
+	orderID := uint64(1)
+	inserted, err := repo.Insert(context.Background(), model.Order{OrderID: orderID})
+	if err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	const callers = 20
+
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := repo.Update(context.Background(), model.Order{
+				OrderID: orderID,
+				Version: inserted.Version,
+			})
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	var wins, conflicts int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case errors.Is(err, ErrVersionConflict):
+			conflicts++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if wins != 1 {
+		t.Fatalf("got %d winning updates, want exactly 1", wins)
+	}
+	if conflicts != callers-1 {
+		t.Fatalf("got %d version conflicts, want %d", conflicts, callers-1)
+	}
+}
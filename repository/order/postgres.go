@@ -0,0 +1,301 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+// pgxPool is the subset of *pgxpool.Pool PostgresRepo needs. It exists so
+// tests can substitute a mock pool (e.g. pgxmock) without a real database,
+// the same way queryer lets findByID run inside or outside a transaction.
+type pgxPool interface {
+	queryer
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// PostgresRepo is a Repo backed by Postgres, for operators who want a
+// durable SQL store instead of Redis. Orders live in the `orders` table;
+// their line items live in a child `line_items` table keyed by order_id.
+// See postgres_schema.sql for the DDL.
+type PostgresRepo struct {
+	Pool pgxPool
+}
+
+func NewPostgresRepo(pool *pgxpool.Pool) *PostgresRepo {
+	return &PostgresRepo{Pool: pool}
+}
+
+func (r *PostgresRepo) Insert(ctx context.Context, order model.Order) (model.Order, error) {
+	if order.ClientOID != zeroUUID {
+		existing, err := r.FindByClientOID(ctx, order.ClientOID)
+		if err == nil {
+			return existing, nil
+		} else if !errors.Is(err, ErrNotExist) {
+			return model.Order{}, err
+		}
+	}
+
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return model.Order{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	const insertOrder = `
+		INSERT INTO orders (order_id, customer_id, client_oid, created_at, shipped_at, completed_at, version)
+		VALUES ($1, $2, NULLIF($3, '00000000-0000-0000-0000-000000000000'), $4, $5, $6, $7)
+	`
+	_, err = tx.Exec(ctx, insertOrder,
+		order.OrderID, order.CustomerID, order.ClientOID,
+		order.CreatedAt, order.ShippedAt, order.CompletedAt, order.Version)
+	if err != nil {
+		// A concurrent Insert can win the race on client_oid between our
+		// FindByClientOID check above and this INSERT; Postgres catches it
+		// as a unique violation on client_oid, so resolve it the same way
+		// as a failed pre-check and hand back the order that actually won.
+		var pgErr *pgconn.PgError
+		if order.ClientOID != zeroUUID && errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			existing, findErr := r.FindByClientOID(ctx, order.ClientOID)
+			if findErr != nil {
+				return model.Order{}, fmt.Errorf("failed to resolve concurrent client oid insert: %w", findErr)
+			}
+			return existing, nil
+		}
+		return model.Order{}, fmt.Errorf("failed to insert order: %w", err)
+	}
+
+	const insertLineItem = `
+		INSERT INTO line_items (order_id, item_id, quantity, price, attributes)
+		VALUES ($1, $2, $3, $4, '{}')
+	`
+	for _, item := range order.LineItems {
+		if _, err := tx.Exec(ctx, insertLineItem, order.OrderID, item.ItemID, item.Quantity, item.Price); err != nil {
+			return model.Order{}, fmt.Errorf("failed to insert line item: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Order{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return order, nil
+}
+
+func (r *PostgresRepo) FindByID(ctx context.Context, id uint64) (model.Order, error) {
+	return r.findByID(ctx, r.Pool, id)
+}
+
+func (r *PostgresRepo) findByID(ctx context.Context, q queryer, id uint64) (model.Order, error) {
+	const orderQuery = `
+		SELECT order_id, customer_id, client_oid, created_at, shipped_at, completed_at, version
+		FROM orders WHERE order_id = $1
+	`
+	var order model.Order
+	err := q.QueryRow(ctx, orderQuery, id).Scan(
+		&order.OrderID, &order.CustomerID, &order.ClientOID,
+		&order.CreatedAt, &order.ShippedAt, &order.CompletedAt, &order.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Order{}, ErrNotExist
+	} else if err != nil {
+		return model.Order{}, fmt.Errorf("failed to find order: %w", err)
+	}
+
+	const lineItemsQuery = `SELECT item_id, quantity, price FROM line_items WHERE order_id = $1`
+	rows, err := q.Query(ctx, lineItemsQuery, id)
+	if err != nil {
+		return model.Order{}, fmt.Errorf("failed to find line items: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item model.LineItem
+		if err := rows.Scan(&item.ItemID, &item.Quantity, &item.Price); err != nil {
+			return model.Order{}, fmt.Errorf("failed to decode line item: %w", err)
+		}
+		order.LineItems = append(order.LineItems, item)
+	}
+	if err := rows.Err(); err != nil {
+		return model.Order{}, fmt.Errorf("failed to find line items: %w", err)
+	}
+
+	return order, nil
+}
+
+func (r *PostgresRepo) FindByClientOID(ctx context.Context, clientOID uuid.UUID) (model.Order, error) {
+	const q = `SELECT order_id FROM orders WHERE client_oid = $1`
+
+	var orderID uint64
+	err := r.Pool.QueryRow(ctx, q, clientOID).Scan(&orderID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model.Order{}, ErrNotExist
+	} else if err != nil {
+		return model.Order{}, fmt.Errorf("failed to find order by client oid: %w", err)
+	}
+
+	return r.FindByID(ctx, orderID)
+}
+
+func (r *PostgresRepo) Update(ctx context.Context, order model.Order) (model.Order, error) {
+	tx, err := r.Pool.Begin(ctx)
+	if err != nil {
+		return model.Order{}, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	current, err := r.findByID(ctx, tx, order.OrderID)
+	if err != nil {
+		return model.Order{}, err
+	}
+
+	if order.Version != current.Version {
+		return current, ErrVersionConflict
+	}
+
+	expectedVersion := current.Version
+	order.Version = current.Version + 1
+
+	// WHERE also matches the version we read, so if another writer commits
+	// between our read and this UPDATE, this UPDATE matches no row instead of
+	// silently overwriting that write - the plain SELECT above isn't on its own enough to
+	// serialize against a concurrent Update in the same window.
+	const updateOrder = `
+		UPDATE orders SET shipped_at = $2, completed_at = $3, version = $4
+		WHERE order_id = $1 AND version = $5
+	`
+	tag, err := tx.Exec(ctx, updateOrder, order.OrderID, order.ShippedAt, order.CompletedAt, order.Version, expectedVersion)
+	if err != nil {
+		return model.Order{}, fmt.Errorf("failed to update order: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		fresh, err := r.findByID(ctx, tx, order.OrderID)
+		if err != nil {
+			return model.Order{}, ErrVersionConflict
+		}
+		return fresh, ErrVersionConflict
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return model.Order{}, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return order, nil
+}
+
+func (r *PostgresRepo) DeletedByID(ctx context.Context, id uint64) error {
+	const q = `DELETE FROM orders WHERE order_id = $1`
+
+	tag, err := r.Pool.Exec(ctx, q, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+
+	if tag.RowsAffected() == 0 {
+		return ErrNotExist
+	}
+
+	return nil
+}
+
+// FindOrders looks orders up by customer, status and/or creation time
+// window, falling back to every order when none of those are set. Status
+// is derived from shipped_at/completed_at rather than stored directly, so
+// it's filtered with a CASE expression instead of a plain column match.
+func (r *PostgresRepo) FindOrders(ctx context.Context, query OrderQuery) (FindResult, error) {
+	const q = `
+		SELECT order_id, customer_id, client_oid, created_at, shipped_at, completed_at, version
+		FROM orders
+		WHERE ($1 = '00000000-0000-0000-0000-000000000000' OR customer_id = $1)
+		  AND ($2 = '' OR
+		       CASE
+		           WHEN completed_at IS NOT NULL THEN 'completed'
+		           WHEN shipped_at IS NOT NULL THEN 'shipped'
+		           ELSE 'open'
+		       END = $2)
+		  AND ($3::timestamptz IS NULL OR created_at >= $3)
+		  AND ($4::timestamptz IS NULL OR created_at <= $4)
+		ORDER BY created_at
+		LIMIT $5 OFFSET $6
+	`
+	// A zero Size means no limit; LIMIT NULL is unbounded in Postgres.
+	var limit *uint64
+	if query.Size > 0 {
+		limit = &query.Size
+	}
+
+	rows, err := r.Pool.Query(ctx, q,
+		query.CustomerID, query.Status, query.CreatedAfter, query.CreatedBefore,
+		limit, query.Offset)
+	if err != nil {
+		return FindResult{}, fmt.Errorf("failed to find orders: %w", err)
+	}
+	defer rows.Close()
+
+	var orders []model.Order
+	for rows.Next() {
+		var order model.Order
+		if err := rows.Scan(
+			&order.OrderID, &order.CustomerID, &order.ClientOID,
+			&order.CreatedAt, &order.ShippedAt, &order.CompletedAt, &order.Version,
+		); err != nil {
+			return FindResult{}, fmt.Errorf("failed to decode order: %w", err)
+		}
+		orders = append(orders, order)
+	}
+	if err := rows.Err(); err != nil {
+		return FindResult{}, fmt.Errorf("failed to find orders: %w", err)
+	}
+
+	for i, order := range orders {
+		items, err := r.lineItems(ctx, order.OrderID)
+		if err != nil {
+			return FindResult{}, err
+		}
+		orders[i].LineItems = items
+	}
+
+	var cursor uint64
+	if query.Size > 0 && uint64(len(orders)) == query.Size {
+		cursor = query.Offset + query.Size
+	}
+
+	return FindResult{Orders: orders, Cursor: cursor}, nil
+}
+
+func (r *PostgresRepo) lineItems(ctx context.Context, orderID uint64) ([]model.LineItem, error) {
+	const q = `SELECT item_id, quantity, price FROM line_items WHERE order_id = $1`
+
+	rows, err := r.Pool.Query(ctx, q, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find line items: %w", err)
+	}
+	defer rows.Close()
+
+	var items []model.LineItem
+	for rows.Next() {
+		var item model.LineItem
+		if err := rows.Scan(&item.ItemID, &item.Quantity, &item.Price); err != nil {
+			return nil, fmt.Errorf("failed to decode line item: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
+// queryer is the subset of pgx's query surface shared by *pgxpool.Pool and
+// pgx.Tx, so findByID can run inside or outside a transaction.
+type queryer interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
@@ -0,0 +1,118 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v3"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+// TestPostgresRepo_Insert_ClientOIDUniqueViolation guards the fix that
+// closes PostgresRepo's idempotency race: a concurrent Insert can win the
+// client_oid UNIQUE constraint between our pre-check and the INSERT, and
+// that must resolve to the winner's order instead of surfacing as a bare
+// error.
+func TestPostgresRepo_Insert_ClientOIDUniqueViolation(t *testing.T) {
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	defer mockPool.Close()
+
+	repo := &PostgresRepo{Pool: mockPool}
+
+	clientOID := uuid.New()
+	existingID := uint64(42)
+
+	mockPool.ExpectQuery(`SELECT order_id FROM orders WHERE client_oid = \$1`).
+		WithArgs(clientOID).
+		WillReturnError(pgx.ErrNoRows)
+
+	mockPool.ExpectBegin()
+	mockPool.ExpectExec(`INSERT INTO orders`).
+		WithArgs(uint64(1), uuid.UUID{}, clientOID, pgxmock.AnyArg(), pgxmock.AnyArg(), pgxmock.AnyArg(), uint64(0)).
+		WillReturnError(&pgconn.PgError{Code: pgerrcode.UniqueViolation, ConstraintName: "orders_client_oid_key"})
+
+	mockPool.ExpectQuery(`SELECT order_id FROM orders WHERE client_oid = \$1`).
+		WithArgs(clientOID).
+		WillReturnRows(pgxmock.NewRows([]string{"order_id"}).AddRow(existingID))
+
+	mockPool.ExpectQuery(`SELECT order_id, customer_id, client_oid, created_at, shipped_at, completed_at, version`).
+		WithArgs(existingID).
+		WillReturnRows(pgxmock.NewRows(
+			[]string{"order_id", "customer_id", "client_oid", "created_at", "shipped_at", "completed_at", "version"},
+		).AddRow(existingID, uuid.New(), clientOID, nil, nil, nil, uint64(0)))
+	mockPool.ExpectQuery(`SELECT item_id, quantity, price FROM line_items`).
+		WithArgs(existingID).
+		WillReturnRows(pgxmock.NewRows([]string{"item_id", "quantity", "price"}))
+
+	mockPool.ExpectRollback()
+
+	got, err := repo.Insert(context.Background(), model.Order{OrderID: 1, ClientOID: clientOID})
+	if err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if got.OrderID != existingID {
+		t.Fatalf("got order %d, want the existing order %d", got.OrderID, existingID)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+// TestPostgresRepo_Update_LostRace guards the fix that serializes Update
+// against a concurrent writer: if the UPDATE's WHERE clause (order_id AND
+// version) matches no rows, someone else committed between our read and
+// our write, and that must surface as ErrVersionConflict rather than a
+// silently "successful" overwrite.
+func TestPostgresRepo_Update_LostRace(t *testing.T) {
+	mockPool, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("NewPool: %v", err)
+	}
+	defer mockPool.Close()
+
+	repo := &PostgresRepo{Pool: mockPool}
+
+	orderID := uint64(1)
+	customerID := uuid.New()
+	orderCols := []string{"order_id", "customer_id", "client_oid", "created_at", "shipped_at", "completed_at", "version"}
+
+	mockPool.ExpectBegin()
+	mockPool.ExpectQuery(`SELECT order_id, customer_id, client_oid, created_at, shipped_at, completed_at, version`).
+		WithArgs(orderID).
+		WillReturnRows(pgxmock.NewRows(orderCols).AddRow(orderID, customerID, zeroUUID, nil, nil, nil, uint64(0)))
+	mockPool.ExpectQuery(`SELECT item_id, quantity, price FROM line_items`).
+		WithArgs(orderID).
+		WillReturnRows(pgxmock.NewRows([]string{"item_id", "quantity", "price"}))
+
+	mockPool.ExpectExec(`UPDATE orders SET`).
+		WithArgs(orderID, pgxmock.AnyArg(), pgxmock.AnyArg(), uint64(1), uint64(0)).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	mockPool.ExpectQuery(`SELECT order_id, customer_id, client_oid, created_at, shipped_at, completed_at, version`).
+		WithArgs(orderID).
+		WillReturnRows(pgxmock.NewRows(orderCols).AddRow(orderID, customerID, zeroUUID, nil, nil, nil, uint64(1)))
+	mockPool.ExpectQuery(`SELECT item_id, quantity, price FROM line_items`).
+		WithArgs(orderID).
+		WillReturnRows(pgxmock.NewRows([]string{"item_id", "quantity", "price"}))
+
+	mockPool.ExpectRollback()
+
+	_, err = repo.Update(context.Background(), model.Order{OrderID: orderID, Version: 0})
+	if !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("got error %v, want ErrVersionConflict", err)
+	}
+
+	if err := mockPool.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package order
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+// OrderQuery describes an order-history lookup: all fields are optional,
+// so a zero-value OrderQuery returns every order.
+type OrderQuery struct {
+	CustomerID    uuid.UUID
+	Status        string // "open", "shipped" or "completed"
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Size          uint64
+	Offset        uint64
+}
+
+const (
+	StatusOpen      = "open"
+	StatusShipped   = "shipped"
+	StatusCompleted = "completed"
+)
+
+// statusOf reports the lifecycle status implied by an order's timestamps.
+func statusOf(order model.Order) string {
+	switch {
+	case order.CompletedAt != nil:
+		return StatusCompleted
+	case order.ShippedAt != nil:
+		return StatusShipped
+	default:
+		return StatusOpen
+	}
+}
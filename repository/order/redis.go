@@ -5,13 +5,23 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+
 	"github.com/thiagobgarc/orders-api/model"
+	"github.com/thiagobgarc/orders-api/pubsub"
 )
 
 type RedisRepo struct {
 	Client *redis.Client
+
+	// Publisher is optional: when set, every successful Insert/Update/
+	// DeletedByID publishes the corresponding order event. It is nil in
+	// tests that don't care about the pub/sub side effects.
+	Publisher *pubsub.Publisher
 }
 
 // orderIDKey generates a key for an order ID.
@@ -22,41 +32,151 @@ func orderIDKey(id uint64) string {
 	return fmt.Sprintf("order:%d", id)
 }
 
+// clientOIDKey generates the key mapping a client-supplied idempotency key
+// to the order ID it originally created.
+func clientOIDKey(id uuid.UUID) string {
+	return fmt.Sprintf("clientoid:%s", id)
+}
+
+// allOrdersKey is a sorted set of every order key, scored by created-at
+// unix time, so FindOrders can page through the full history without a
+// filter.
+const allOrdersKey = "orders:all"
+
+// customerSetKey is a sorted set of the order keys belonging to a single
+// customer, scored by created-at unix time.
+func customerSetKey(customerID uuid.UUID) string {
+	return fmt.Sprintf("orders:by-customer:%s", customerID)
+}
+
+// statusSetKey is a sorted set of the order keys currently in a given
+// status, scored by created-at unix time.
+func statusSetKey(status string) string {
+	return fmt.Sprintf("orders:by-status:%s", status)
+}
+
+var zeroUUID uuid.UUID
+
+// createdAtScore turns an order's CreatedAt into the score used across the
+// sorted-set indexes.
+func createdAtScore(order model.Order) float64 {
+	if order.CreatedAt == nil {
+		return 0
+	}
+	return float64(order.CreatedAt.Unix())
+}
+
 // Insert inserts an order into the RedisRepo.
 //
 // The function takes a context.Context and a model.Order as input parameters.
 // It serializes the order into JSON format and inserts it into Redis using a key generated from the order ID.
-// The function returns an error if there is any issue with encoding the order, inserting the order into Redis, or committing the transaction.
-func (r *RedisRepo) Insert(ctx context.Context, order model.Order) error {
+// If order.ClientOID is set and already maps to an existing order, that order
+// is returned unchanged instead of inserting a duplicate - this makes Create
+// safe to retry. The function returns an error if there is any issue with
+// encoding the order, inserting the order into Redis, or committing the
+// transaction.
+func (r *RedisRepo) Insert(ctx context.Context, order model.Order) (model.Order, error) {
+	if order.ClientOID != zeroUUID {
+		existing, err := r.FindByClientOID(ctx, order.ClientOID)
+		if err == nil {
+			return existing, nil
+		} else if !errors.Is(err, ErrNotExist) {
+			return model.Order{}, fmt.Errorf("failed to check client oid: %w", err)
+		}
+	}
+
 	data, err := json.Marshal(order)
 	if err != nil {
-		return fmt.Errorf("failed to encode order! %m", err)
+		return model.Order{}, fmt.Errorf("failed to encode order: %w", err)
 	}
 
 	key := orderIDKey(order.OrderID)
 
 	txn := r.Client.TxPipeline()
 
-	res := r.Client.SetNX(ctx, key, string(data), 0)
-	if err := res.Err(); err != nil {
+	orderSet := txn.SetNX(ctx, key, string(data), 0)
+	if err := orderSet.Err(); err != nil {
+		txn.Discard()
+		return model.Order{}, fmt.Errorf("failed to insert order: %w", err)
+	}
+
+	var clientOIDSet *redis.BoolCmd
+	if order.ClientOID != zeroUUID {
+		clientOIDSet = txn.SetNX(ctx, clientOIDKey(order.ClientOID), order.OrderID, 0)
+	}
+
+	score := createdAtScore(order)
+
+	if err := txn.ZAdd(ctx, allOrdersKey, redis.Z{Score: score, Member: key}).Err(); err != nil {
 		txn.Discard()
-		return fmt.Errorf("failed to insert order! %m", err)
+		return model.Order{}, fmt.Errorf("failed to insert order: %w", err)
 	}
 
-	if err != txn.SAdd(ctx, "orders", key).Err(); err != nil {
+	if err := txn.ZAdd(ctx, customerSetKey(order.CustomerID), redis.Z{Score: score, Member: key}).Err(); err != nil {
 		txn.Discard()
-		return fmt.Errorf("failed to insert order! %m", err)
+		return model.Order{}, fmt.Errorf("failed to insert order: %w", err)
 	}
 
-	if err := txn.Commit(ctx).Err(); err != nil {
-		return fmt.Errorf("failed to insert order! %m", err)
+	if err := txn.ZAdd(ctx, statusSetKey(statusOf(order)), redis.Z{Score: score, Member: key}).Err(); err != nil {
+		txn.Discard()
+		return model.Order{}, fmt.Errorf("failed to insert order: %w", err)
 	}
 
-	return nil
+	if _, err := txn.Exec(ctx); err != nil {
+		return model.Order{}, fmt.Errorf("failed to insert order: %w", err)
+	}
+
+	// The ClientOID SetNX above only queues the command; its .Err() is always
+	// nil before Exec runs. Check the actual result now - a losing SETNX
+	// means a concurrent Insert already claimed this ClientOID, so fall back
+	// to whatever it committed instead of leaving two orders for one
+	// client-submitted idempotency key.
+	if clientOIDSet != nil && !clientOIDSet.Val() {
+		// This call's own order was committed by the same transaction; remove
+		// it and its index entries so only the winner's order stays stored.
+		if orderSet.Val() {
+			cleanup := r.Client.TxPipeline()
+			cleanup.Del(ctx, key)
+			cleanup.ZRem(ctx, allOrdersKey, key)
+			cleanup.ZRem(ctx, customerSetKey(order.CustomerID), key)
+			cleanup.ZRem(ctx, statusSetKey(statusOf(order)), key)
+			if _, err := cleanup.Exec(ctx); err != nil {
+				return model.Order{}, fmt.Errorf("failed to discard duplicate order: %w", err)
+			}
+		}
+
+		existing, err := r.FindByClientOID(ctx, order.ClientOID)
+		if err != nil {
+			return model.Order{}, fmt.Errorf("failed to resolve concurrent client oid insert: %w", err)
+		}
+		return existing, nil
+	}
+
+	r.publish(ctx, pubsub.EventOrderCreated, order)
+
+	return order, nil
 }
 
 var ErrNotExist = errors.New("order does not exist")
 
+// FindByClientOID looks up the order that was created with the given
+// client-supplied idempotency key.
+func (r *RedisRepo) FindByClientOID(ctx context.Context, clientOID uuid.UUID) (model.Order, error) {
+	orderIDStr, err := r.Client.Get(ctx, clientOIDKey(clientOID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return model.Order{}, ErrNotExist
+	} else if err != nil {
+		return model.Order{}, fmt.Errorf("failed to find order by client oid: %w", err)
+	}
+
+	orderID, err := strconv.ParseUint(orderIDStr, 10, 64)
+	if err != nil {
+		return model.Order{}, fmt.Errorf("failed to parse order id for client oid: %w", err)
+	}
+
+	return r.FindByID(ctx, orderID)
+}
+
 // FindByID finds an order by its ID.
 //
 // Parameters:
@@ -73,13 +193,12 @@ func (r *RedisRepo) FindByID(ctx context.Context, id uint64) (model.Order, error
 	if errors.Is(err, redis.Nil) {
 		return model.Order{}, ErrNotExist
 	} else if err != nil {
-		return model.Order{}, fmt.Errorf("failed to find order! %m", err)
+		return model.Order{}, fmt.Errorf("failed to find order: %w", err)
 	}
 
 	var order model.Order
-	err = json.Unmarshal(([]byte(value), &order))
-	if err != nil {
-		return model.Order{}, fmt.Errorf("failed to decode order! %m", err)
+	if err := json.Unmarshal([]byte(value), &order); err != nil {
+		return model.Order{}, fmt.Errorf("failed to decode order: %w", err)
 	}
 
 	return order, nil
@@ -93,52 +212,176 @@ func (r *RedisRepo) FindByID(ctx context.Context, id uint64) (model.Order, error
 func (r *RedisRepo) DeletedByID(ctx context.Context, id uint64) error {
 	key := orderIDKey(id)
 
+	order, err := r.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
 	txn := r.Client.TxPipeline()
 
-	err := r.Client.Del(ctx, key).Err()
-	if errors.Is(err, redis.Nil) {
-		return ErrNotExist
-	} else if err != nil {
-		return fmt.Errorf("failed to delete order! %m", err)
+	if err := txn.Del(ctx, key).Err(); err != nil {
+		txn.Discard()
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+
+	if err := txn.ZRem(ctx, allOrdersKey, key).Err(); err != nil {
+		txn.Discard()
+		return fmt.Errorf("failed to delete order: %w", err)
+	}
+
+	if err := txn.ZRem(ctx, customerSetKey(order.CustomerID), key).Err(); err != nil {
+		txn.Discard()
+		return fmt.Errorf("failed to delete order: %w", err)
 	}
 
-	if err := txn.SRem(ctx, "orders", key).Err(); err != nil {
+	if err := txn.ZRem(ctx, statusSetKey(statusOf(order)), key).Err(); err != nil {
 		txn.Discard()
-		return fmt.Errorf("failed to delete order! %m", err)
+		return fmt.Errorf("failed to delete order: %w", err)
 	}
 
-	if _. err := txn.Exec(ctx); err != nil {
-		return fmt.Errorf("failed to delete order! %m", err)
+	if _, err := txn.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete order: %w", err)
 	}
 
+	r.publish(ctx, pubsub.EventOrderDeleted, order)
+
 	return nil
 }
 
+// ErrVersionConflict is returned by Update when order.Version doesn't match
+// the version currently stored for that order. The caller-visible current
+// order is returned alongside it so the client can retry against a fresh
+// copy.
+var ErrVersionConflict = errors.New("order version conflict")
+
+// maxUpdateRetries bounds how many times Update retries the WATCH/MULTI/EXEC
+// transaction after Redis reports the watched key changed between the GET
+// and the EXEC (redis.TxFailedErr) - the race WATCH exists to catch, not a
+// version mismatch our own code observed.
+const maxUpdateRetries = 3
+
 // Update updates the RedisRepo with the given order.
 //
 // It takes a context.Context object and a model.Order object as parameters.
-// It returns an error.
-func (r *RedisRepo) Update(ctx context.Context, order model.Order) error {
-	data, err := json.Marshal(order)
-	if err != nil {
-		return fmt.Errorf("failed to encode order! %m", err)
-	}
-
+// The update happens inside a WATCH/MULTI/EXEC transaction: order.Version
+// must match the version currently stored, otherwise the write is aborted
+// and ErrVersionConflict is returned along with the order as it currently
+// stands server-side. On success Update returns the stored order with its
+// Version incremented.
+func (r *RedisRepo) Update(ctx context.Context, order model.Order) (model.Order, error) {
 	key := orderIDKey(order.OrderID)
+	wantVersion := order.Version
+
+	for attempt := 0; ; attempt++ {
+		toWrite := order
+		toWrite.Version = wantVersion
+
+		var (
+			current  model.Order
+			conflict bool
+		)
+
+		txf := func(tx *redis.Tx) error {
+			value, err := tx.Get(ctx, key).Result()
+			if errors.Is(err, redis.Nil) {
+				return ErrNotExist
+			} else if err != nil {
+				return fmt.Errorf("failed to find order: %w", err)
+			}
+
+			if err := json.Unmarshal([]byte(value), &current); err != nil {
+				return fmt.Errorf("failed to decode order: %w", err)
+			}
+
+			if toWrite.Version != current.Version {
+				conflict = true
+				return ErrVersionConflict
+			}
+
+			toWrite.Version = current.Version + 1
+
+			data, err := json.Marshal(toWrite)
+			if err != nil {
+				return fmt.Errorf("failed to encode order: %w", err)
+			}
+
+			oldStatus, newStatus := statusOf(current), statusOf(toWrite)
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				pipe.Set(ctx, key, string(data), 0)
+				if oldStatus != newStatus {
+					pipe.ZRem(ctx, statusSetKey(oldStatus), key)
+					pipe.ZAdd(ctx, statusSetKey(newStatus), redis.Z{Score: createdAtScore(toWrite), Member: key})
+				}
+				return nil
+			})
+			return err
+		}
 
-	err = r.Client.SetXX(ctx, key, string(data), 0).Err()
-	if errors.Is(err, redis.nil) {
-		return ErrNotExist
-	} else if err != nil {
-		return fmt.Errorf("failed to update order! %m", err)
+		err := r.Client.Watch(ctx, txf, key)
+		if err == nil {
+			r.publish(ctx, eventKindForUpdate(toWrite), toWrite)
+			return toWrite, nil
+		}
+
+		if conflict {
+			return current, ErrVersionConflict
+		}
+
+		if errors.Is(err, redis.TxFailedErr) && attempt < maxUpdateRetries {
+			continue
+		}
+
+		if errors.Is(err, redis.TxFailedErr) {
+			// Exhausted our retries on a key that keeps changing out from
+			// under us; report it the same way a version mismatch would be,
+			// with the freshest copy we can read for the caller to retry
+			// against.
+			if fresh, findErr := r.FindByID(ctx, order.OrderID); findErr == nil {
+				return fresh, ErrVersionConflict
+			}
+			return model.Order{}, ErrVersionConflict
+		}
+
+		if errors.Is(err, ErrNotExist) {
+			return model.Order{}, ErrNotExist
+		}
+
+		return model.Order{}, fmt.Errorf("failed to update order: %w", err)
 	}
+}
 
-	return nil
+// eventKindForUpdate picks the event kind that best describes an update,
+// so subscribers can tell a shipment or completion apart from a plain edit.
+func eventKindForUpdate(order model.Order) pubsub.EventKind {
+	switch statusOf(order) {
+	case StatusCompleted:
+		return pubsub.EventOrderCompleted
+	case StatusShipped:
+		return pubsub.EventOrderShipped
+	default:
+		return pubsub.EventOrderUpdated
+	}
 }
 
-type FindALLPage struct {
-	Size uint64
-	Offset uint64
+// publish best-effort publishes an order event; a pub/sub hiccup should
+// never fail the write it's reporting on.
+func (r *RedisRepo) publish(ctx context.Context, kind pubsub.EventKind, order model.Order) {
+	if r.Publisher == nil {
+		return
+	}
+
+	event := pubsub.OrderEvent{
+		Kind:       kind,
+		OrderID:    order.OrderID,
+		CustomerID: order.CustomerID,
+		Timestamp:  time.Now().UTC(),
+		Order:      order,
+	}
+
+	if err := r.Publisher.Publish(ctx, event); err != nil {
+		fmt.Println("failed to publish order event:", err)
+	}
 }
 
 type FindResult struct {
@@ -146,44 +389,94 @@ type FindResult struct {
 	Cursor uint64
 }
 
-// FindAll retrieves all the records from the RedisRepo.
+// FindOrders looks orders up by customer, status and/or creation time
+// window, falling back to every order when none of those are set.
 //
-// It takes the following parameters:
-// - ctx: the context.Context object for handling cancellation and timeouts.
-// - page: the FindAllPage object containing the page offset and size.
-//
-// It returns a FindResult object and an error if any occurred.
-func (r *RedisRepo) FindAll(ctx context.Context, page FindAllPage) (FindResult, error) {
-	res := r.Client.SScan(ctx, "orders", page.Offset, "x", int64(page.Size))
+// CustomerID and Status are each backed by a sorted set scored by
+// created-at unix time (orders:by-customer:{id}, orders:by-status:{name});
+// when both are present they're combined with ZINTERSTORE into a scratch
+// key before the time window and pagination are applied. The stored
+// members are then MGET in one round trip.
+func (r *RedisRepo) FindOrders(ctx context.Context, query OrderQuery) (FindResult, error) {
+	hasCustomer := query.CustomerID != zeroUUID
+	hasStatus := query.Status != ""
+
+	setKey := allOrdersKey
+
+	switch {
+	case hasCustomer && hasStatus:
+		setKey = fmt.Sprintf("orders:tmp:%s:%s", query.CustomerID, query.Status)
+
+		err := r.Client.ZInterStore(ctx, setKey, &redis.ZStore{
+			Keys:      []string{customerSetKey(query.CustomerID), statusSetKey(query.Status)},
+			Aggregate: "MIN",
+		}).Err()
+		if err != nil {
+			return FindResult{}, fmt.Errorf("failed to combine order indexes: %w", err)
+		}
+		defer r.Client.Del(ctx, setKey)
+	case hasCustomer:
+		setKey = customerSetKey(query.CustomerID)
+	case hasStatus:
+		setKey = statusSetKey(query.Status)
+	}
+
+	minScore, maxScore := "-inf", "+inf"
+	if query.CreatedAfter != nil {
+		minScore = strconv.FormatInt(query.CreatedAfter.Unix(), 10)
+	}
+	if query.CreatedBefore != nil {
+		maxScore = strconv.FormatInt(query.CreatedBefore.Unix(), 10)
+	}
+
+	// A zero Size means no limit; Redis reads a negative LIMIT count that way.
+	count := int64(query.Size)
+	if count == 0 {
+		count = -1
+	}
 
-	keys, cursor, err := res.Result()
+	keys, err := r.Client.ZRangeByScore(ctx, setKey, &redis.ZRangeBy{
+		Min:    minScore,
+		Max:    maxScore,
+		Offset: int64(query.Offset),
+		Count:  count,
+	}).Result()
 	if err != nil {
-		return FindResult{}, fmt.Errorf("failed to find orders! %m", err)
+		return FindResult{}, fmt.Errorf("failed to find orders: %w", err)
 	}
 
 	if len(keys) == 0 {
-		return FindResult{
-			Orders: []model.Order{},
-		}, nil
+		return FindResult{Orders: []model.Order{}}, nil
 	}
 
 	xs, err := r.Client.MGet(ctx, keys...).Result()
 	if err != nil {
-		return FindResult{}, fmt.Errorf("failed to find orders! %m", err)
+		return FindResult{}, fmt.Errorf("failed to find orders: %w", err)
 	}
 
-	orders := make([]model.Order, len(xs))
+	orders := make([]model.Order, 0, len(xs))
 
-	for i, x := range xs {
-		x := x.(string)
-		var order model.Order
+	for _, x := range xs {
+		// A key can be deleted (DeletedByID) or moved between status sets
+		// (Update) between the ZRangeByScore read above and this MGet, in
+		// which case Redis returns nil for that member instead of a string.
+		// Skip it rather than panicking on the type assertion.
+		value, ok := x.(string)
+		if !ok {
+			continue
+		}
 
-		err := json.Unmarshal(([]byte(x), &order))
-		if err != nil {
-			return FindResult{}, fmt.Errorf("failed to decode order! %m", err)
+		var order model.Order
+		if err := json.Unmarshal([]byte(value), &order); err != nil {
+			return FindResult{}, fmt.Errorf("failed to decode order: %w", err)
 		}
 
-		orders[i] = order
+		orders = append(orders, order)
+	}
+
+	var cursor uint64
+	if query.Size > 0 && uint64(len(keys)) == query.Size {
+		cursor = query.Offset + query.Size
 	}
 
 	return FindResult{
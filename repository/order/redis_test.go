@@ -0,0 +1,121 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+func newTestRedisRepo(t *testing.T) *RedisRepo {
+	t.Helper()
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return &RedisRepo{Client: client}
+}
+
+// TestRedisRepo_Insert_ConcurrentClientOID guards the fix in 9f304b8: the
+// clientoid SETNX result must actually be checked after Exec, or two
+// concurrent Inserts with the same ClientOID both commit distinct orders.
+func TestRedisRepo_Insert_ConcurrentClientOID(t *testing.T) {
+	repo := newTestRedisRepo(t)
+	clientOID := uuid.New()
+
+	const callers = 20
+
+	var wg sync.WaitGroup
+	results := make([]model.Order, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			order, err := repo.Insert(context.Background(), model.Order{
+				OrderID:   uint64(i) + 1,
+				ClientOID: clientOID,
+			})
+			if err != nil {
+				t.Errorf("Insert: %v", err)
+				return
+			}
+			results[i] = order
+		}(i)
+	}
+	wg.Wait()
+
+	first := results[0].OrderID
+	for i, order := range results {
+		if order.OrderID != first {
+			t.Fatalf("caller %d got order %d, want %d (same ClientOID must resolve to one order)", i, order.OrderID, first)
+		}
+	}
+
+	byClientOID, err := repo.FindByClientOID(context.Background(), clientOID)
+	if err != nil {
+		t.Fatalf("FindByClientOID: %v", err)
+	}
+	if byClientOID.OrderID != first {
+		t.Fatalf("clientoid key resolves to order %d, want %d", byClientOID.OrderID, first)
+	}
+}
+
+// TestRedisRepo_Update_ConcurrentVersion guards the fix in db76801: a
+// concurrent writer that wins the WATCH/MULTI/EXEC race must surface as
+// ErrVersionConflict to every loser, not a generic transaction-failed error.
+func TestRedisRepo_Update_ConcurrentVersion(t *testing.T) {
+	repo := newTestRedisRepo(t)
+
+	orderID := uint64(1)
+	inserted, err := repo.Insert(context.Background(), model.Order{OrderID: orderID})
+	if err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	const callers = 20
+
+	var wg sync.WaitGroup
+	errs := make([]error, callers)
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := repo.Update(context.Background(), model.Order{
+				OrderID: orderID,
+				Version: inserted.Version,
+			})
+			errs[i] = err
+		}(i)
+	}
+	wg.Wait()
+
+	var wins, conflicts int
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case errors.Is(err, ErrVersionConflict):
+			conflicts++
+		default:
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if wins != 1 {
+		t.Fatalf("got %d winning updates, want exactly 1", wins)
+	}
+	if conflicts != callers-1 {
+		t.Fatalf("got %d version conflicts, want %d", conflicts, callers-1)
+	}
+}
@@ -0,0 +1,25 @@
+package order
+
+import (
+	"context"
+
+	"github.com/thiagobgarc/orders-api/model"
+)
+
+// Repo is the storage contract the handler layer depends on. RedisRepo is
+// the default, production backend; InMemoryRepo and PostgresRepo satisfy
+// the same contract so the backend can be swapped via ORDERS_BACKEND
+// without touching handler code.
+type Repo interface {
+	Insert(ctx context.Context, order model.Order) (model.Order, error)
+	FindByID(ctx context.Context, id uint64) (model.Order, error)
+	Update(ctx context.Context, order model.Order) (model.Order, error)
+	DeletedByID(ctx context.Context, id uint64) error
+	FindOrders(ctx context.Context, query OrderQuery) (FindResult, error)
+}
+
+var (
+	_ Repo = (*RedisRepo)(nil)
+	_ Repo = (*InMemoryRepo)(nil)
+	_ Repo = (*PostgresRepo)(nil)
+)